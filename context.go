@@ -0,0 +1,54 @@
+package persistent_cache
+
+import (
+	"context"
+	"time"
+)
+
+// ctxKey is the unexported type for context values set by WithTTL,
+// WithBypass and WithStaleOK, so they can't collide with keys set by other
+// packages.
+type ctxKey int
+
+const (
+	ctxKeyTTL ctxKey = iota
+	ctxKeyBypass
+	ctxKeyStaleOK
+)
+
+// WithTTL overrides the ttl a MemoizeCtx* wrapper was constructed with for
+// calls made with the returned context, e.g. to cache a specific request
+// for longer or shorter than the wrapper's default.
+func WithTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, ctxKeyTTL, ttl)
+}
+
+// WithBypass marks the returned context so a MemoizeCtx* wrapper skips the
+// cache lookup: fn runs, and its result still overwrites whatever was
+// cached. With singleflight enabled, a call for the same key that is
+// already in flight is joined and its result shared instead.
+func WithBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKeyBypass, true)
+}
+
+// WithStaleOK allows a MemoizeCtx* wrapper to return an entry up to
+// maxStale past its ttl, triggering an asynchronous refresh in the
+// background rather than making the caller wait on fn.
+func WithStaleOK(ctx context.Context, maxStale time.Duration) context.Context {
+	return context.WithValue(ctx, ctxKeyStaleOK, maxStale)
+}
+
+func ttlFromContext(ctx context.Context) (time.Duration, bool) {
+	ttl, ok := ctx.Value(ctxKeyTTL).(time.Duration)
+	return ttl, ok
+}
+
+func bypassFromContext(ctx context.Context) bool {
+	bypass, _ := ctx.Value(ctxKeyBypass).(bool)
+	return bypass
+}
+
+func staleOKFromContext(ctx context.Context) (time.Duration, bool) {
+	maxStale, ok := ctx.Value(ctxKeyStaleOK).(time.Duration)
+	return maxStale, ok
+}
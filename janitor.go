@@ -0,0 +1,37 @@
+package persistent_cache
+
+import (
+	"log/slog"
+	"time"
+)
+
+// janitor periodically sweeps expired entries from a Sweeper-capable
+// Store. The pattern mirrors pmylund/go-cache's janitor: it runs on its
+// own goroutine until stop is closed.
+type janitor struct {
+	interval time.Duration
+	stop     chan struct{}
+}
+
+func (j *janitor) run(store Sweeper, onEvicted func(funcName, key string)) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			evicted, err := store.Sweep(time.Now().UTC())
+			if err != nil {
+				slog.Debug("persistent_cache: janitor sweep error", "error", err)
+				continue
+			}
+			if onEvicted != nil {
+				for _, e := range evicted {
+					onEvicted(e.FuncName, e.Key)
+				}
+			}
+		case <-j.stop:
+			return
+		}
+	}
+}
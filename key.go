@@ -0,0 +1,97 @@
+package persistent_cache
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/mitchellh/hashstructure/v2"
+)
+
+// KeyFunc hashes a function's arguments into a cache key. Memoize* accepts
+// one via WithKeyFunc for callers who know more about their argument types
+// than the default can (e.g. to ignore a field, or to hash something gob
+// can't encode).
+type KeyFunc func(args ...any) (string, error)
+
+// MemoizeOption configures a Memoize* wrapper.
+type MemoizeOption func(*memoizeConfig)
+
+// WithKeyFunc overrides the cache key derivation used by a Memoize*
+// wrapper, in place of the default gob+sha256 hash.
+func WithKeyFunc(fn KeyFunc) MemoizeOption {
+	return func(cfg *memoizeConfig) {
+		cfg.keyFunc = fn
+	}
+}
+
+// WithCache routes a Memoize0..Memoize9 or MemoizeN wrapper through c
+// instead of the package-level default Cache, so multiple Cache instances
+// (e.g. backed by different Stores) can coexist without each wrapper's
+// signature having to take a *Cache positionally. MemoizeErr* and
+// MemoizeCtx* take their Cache as a parameter and ignore this option.
+func WithCache(c *Cache) MemoizeOption {
+	return func(cfg *memoizeConfig) {
+		cfg.cache = c
+	}
+}
+
+type memoizeConfig struct {
+	keyFunc KeyFunc
+	cache   *Cache
+}
+
+func _newMemoizeConfig(opts []MemoizeOption) memoizeConfig {
+	cfg := memoizeConfig{keyFunc: _defaultKeyFunc}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// _registerConcreteTypes registers each arg's concrete type with gob
+// before _serialize gob-encodes the argument tuple. gob only needs a type
+// pre-registered when it appears inside an interface-typed slot — which
+// every element of the []any tuple is — so without this an ordinary
+// struct, map, or slice argument makes the whole encode fail and
+// _defaultKeyFunc silently falls back to hashstructure for it. Register is
+// idempotent and safe to call repeatedly; chans and funcs aren't
+// gob-encodable at all, so there's no point registering them.
+func _registerConcreteTypes(args []any) {
+	for _, a := range args {
+		if a == nil {
+			continue
+		}
+		switch reflect.TypeOf(a).Kind() {
+		case reflect.Chan, reflect.Func:
+			continue
+		}
+		gob.Register(a)
+	}
+}
+
+// _defaultKeyFunc hashes args into a deterministic, collision-resistant
+// cache key by gob-encoding the argument tuple and taking its sha256, so
+// e.g. []int{1, 2} and []string{"1 2"} (both "[1 2]" under %v) can no
+// longer collide the way they did under a %v-formatted key, and
+// struct/slice/map args round-trip correctly. Arguments gob rejects fall
+// back to a reflective structural hash, which — like gob — ignores
+// unexported struct fields. Channels and funcs are rejected by both, so
+// for them an error is returned and no key is produced.
+func _defaultKeyFunc(args ...any) (string, error) {
+	_registerConcreteTypes(args)
+	data, err := _serialize(args)
+	if err == nil {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:]), nil
+	}
+
+	sum, hashErr := hashstructure.Hash(args, hashstructure.FormatV2, nil)
+	if hashErr != nil {
+		return "", fmt.Errorf("persistent_cache: cannot hash arguments: gob: %w, hashstructure: %v", err, hashErr)
+	}
+	return strconv.FormatUint(sum, 16), nil
+}
@@ -0,0 +1,56 @@
+package persistent_cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+// keyTestStruct is a package-level named type so gob.Register (called by
+// _registerConcreteTypes) can produce a stable, collision-free name for it.
+type keyTestStruct struct {
+	A int
+	B string
+}
+
+// TestDefaultKeyFuncUsesGobSha256ForStructsAndMaps checks that ordinary
+// struct/map arguments hit the primary gob+sha256 digest path instead of
+// silently falling back to hashstructure, which used to happen because
+// their concrete types were never registered with gob.
+func TestDefaultKeyFuncUsesGobSha256ForStructsAndMaps(t *testing.T) {
+	for _, args := range [][]any{
+		{keyTestStruct{A: 1, B: "x"}},
+		{map[string]int{"a": 1}},
+	} {
+		key, err := _defaultKeyFunc(args...)
+		if err != nil {
+			t.Fatalf("_defaultKeyFunc(%v): %v", args, err)
+		}
+
+		data, serializeErr := _serialize(args)
+		if serializeErr != nil {
+			t.Fatalf("_serialize(%v): %v", args, serializeErr)
+		}
+		sum := sha256.Sum256(data)
+		if want := hex.EncodeToString(sum[:]); key != want {
+			t.Fatalf("_defaultKeyFunc(%v) = %q, want sha256 digest %q (fell back to hashstructure?)", args, key, want)
+		}
+	}
+}
+
+// TestDefaultKeyFuncDistinguishesSliceSpacing checks that []int{1, 2} and
+// []string{"1 2"}, which both format as "[1 2]" under %v and so collided
+// under a %v-formatted key, no longer collapse into one key.
+func TestDefaultKeyFuncDistinguishesSliceSpacing(t *testing.T) {
+	k1, err := _defaultKeyFunc([]int{1, 2})
+	if err != nil {
+		t.Fatalf("_defaultKeyFunc: %v", err)
+	}
+	k2, err := _defaultKeyFunc([]string{"1 2"})
+	if err != nil {
+		t.Fatalf("_defaultKeyFunc: %v", err)
+	}
+	if k1 == k2 {
+		t.Fatalf("[]int{1, 2} and []string{\"1 2\"} hashed to the same key %q", k1)
+	}
+}
@@ -0,0 +1,519 @@
+package persistent_cache
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// _refreshStaleAsync refreshes funcName/key in the background after a
+// MemoizeCtx* wrapper has already returned a stale hit to its caller. It
+// runs fn against a context detached from ctx's cancellation (so the
+// caller returning, or its request ending, doesn't cut the refresh off)
+// and shares c's singleflight group so concurrent stale hits for the same
+// key only trigger one refresh.
+func _refreshStaleAsync[R any](ctx context.Context, c *Cache, funcName, key string, ttl time.Duration, fn func(context.Context) (R, error)) {
+	detached := context.WithoutCancel(ctx)
+	go func() {
+		_, _ = _callMemoizedErr(c, funcName, key, func() (R, error) {
+			result, err := fn(detached)
+			if err != nil {
+				return result, err
+			}
+			_cacheSetContext(detached, c, funcName, key, result, ttl)
+			return result, nil
+		})
+	}()
+}
+
+func MemoizeCtx0[R any](c *Cache, ttl time.Duration, fn func(context.Context) (R, error), opts ...MemoizeOption) func(context.Context) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(ctx context.Context) (R, error) {
+		key, err := cfg.keyFunc()
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(ctx)
+		}
+
+		effectiveTTL := ttl
+		if override, ok := ttlFromContext(ctx); ok {
+			effectiveTTL = override
+		}
+
+		if !bypassFromContext(ctx) {
+			if item, ts, found := _cacheGetRawContext[R](ctx, c, func_name, key); found {
+				age := time.Now().UTC().Sub(ts)
+				if age < effectiveTTL {
+					slog.Debug("Cache hit:", "key", key)
+					return item, nil
+				}
+				if maxStale, ok := staleOKFromContext(ctx); ok && age < effectiveTTL+maxStale {
+					slog.Debug("Cache hit (stale):", "key", key)
+					_refreshStaleAsync(ctx, c, func_name, key, effectiveTTL, func(rctx context.Context) (R, error) { return fn(rctx) })
+					return item, nil
+				}
+				_ = c._storeDeleteContext(ctx, func_name, key)
+			}
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(ctx)
+			if fnErr != nil {
+				return r, fnErr
+			}
+			_cacheSetContext(ctx, c, func_name, key, r, effectiveTTL)
+			return r, nil
+		})
+		if err != nil {
+			slog.Debug("Cache miss (error, not cached):", "key", key)
+			return result, err
+		}
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
+
+func MemoizeCtx1[A, R any](c *Cache, ttl time.Duration, fn func(context.Context, A) (R, error), opts ...MemoizeOption) func(context.Context, A) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(ctx context.Context, arg1 A) (R, error) {
+		key, err := cfg.keyFunc(arg1)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(ctx, arg1)
+		}
+
+		effectiveTTL := ttl
+		if override, ok := ttlFromContext(ctx); ok {
+			effectiveTTL = override
+		}
+
+		if !bypassFromContext(ctx) {
+			if item, ts, found := _cacheGetRawContext[R](ctx, c, func_name, key); found {
+				age := time.Now().UTC().Sub(ts)
+				if age < effectiveTTL {
+					slog.Debug("Cache hit:", "key", key)
+					return item, nil
+				}
+				if maxStale, ok := staleOKFromContext(ctx); ok && age < effectiveTTL+maxStale {
+					slog.Debug("Cache hit (stale):", "key", key)
+					_refreshStaleAsync(ctx, c, func_name, key, effectiveTTL, func(rctx context.Context) (R, error) { return fn(rctx, arg1) })
+					return item, nil
+				}
+				_ = c._storeDeleteContext(ctx, func_name, key)
+			}
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(ctx, arg1)
+			if fnErr != nil {
+				return r, fnErr
+			}
+			_cacheSetContext(ctx, c, func_name, key, r, effectiveTTL)
+			return r, nil
+		})
+		if err != nil {
+			slog.Debug("Cache miss (error, not cached):", "key", key)
+			return result, err
+		}
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
+
+func MemoizeCtx2[A, B, R any](c *Cache, ttl time.Duration, fn func(context.Context, A, B) (R, error), opts ...MemoizeOption) func(context.Context, A, B) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(ctx context.Context, arg1 A, arg2 B) (R, error) {
+		key, err := cfg.keyFunc(arg1, arg2)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(ctx, arg1, arg2)
+		}
+
+		effectiveTTL := ttl
+		if override, ok := ttlFromContext(ctx); ok {
+			effectiveTTL = override
+		}
+
+		if !bypassFromContext(ctx) {
+			if item, ts, found := _cacheGetRawContext[R](ctx, c, func_name, key); found {
+				age := time.Now().UTC().Sub(ts)
+				if age < effectiveTTL {
+					slog.Debug("Cache hit:", "key", key)
+					return item, nil
+				}
+				if maxStale, ok := staleOKFromContext(ctx); ok && age < effectiveTTL+maxStale {
+					slog.Debug("Cache hit (stale):", "key", key)
+					_refreshStaleAsync(ctx, c, func_name, key, effectiveTTL, func(rctx context.Context) (R, error) { return fn(rctx, arg1, arg2) })
+					return item, nil
+				}
+				_ = c._storeDeleteContext(ctx, func_name, key)
+			}
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(ctx, arg1, arg2)
+			if fnErr != nil {
+				return r, fnErr
+			}
+			_cacheSetContext(ctx, c, func_name, key, r, effectiveTTL)
+			return r, nil
+		})
+		if err != nil {
+			slog.Debug("Cache miss (error, not cached):", "key", key)
+			return result, err
+		}
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
+
+func MemoizeCtx3[A, B, C, R any](c *Cache, ttl time.Duration, fn func(context.Context, A, B, C) (R, error), opts ...MemoizeOption) func(context.Context, A, B, C) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(ctx context.Context, arg1 A, arg2 B, arg3 C) (R, error) {
+		key, err := cfg.keyFunc(arg1, arg2, arg3)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(ctx, arg1, arg2, arg3)
+		}
+
+		effectiveTTL := ttl
+		if override, ok := ttlFromContext(ctx); ok {
+			effectiveTTL = override
+		}
+
+		if !bypassFromContext(ctx) {
+			if item, ts, found := _cacheGetRawContext[R](ctx, c, func_name, key); found {
+				age := time.Now().UTC().Sub(ts)
+				if age < effectiveTTL {
+					slog.Debug("Cache hit:", "key", key)
+					return item, nil
+				}
+				if maxStale, ok := staleOKFromContext(ctx); ok && age < effectiveTTL+maxStale {
+					slog.Debug("Cache hit (stale):", "key", key)
+					_refreshStaleAsync(ctx, c, func_name, key, effectiveTTL, func(rctx context.Context) (R, error) { return fn(rctx, arg1, arg2, arg3) })
+					return item, nil
+				}
+				_ = c._storeDeleteContext(ctx, func_name, key)
+			}
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(ctx, arg1, arg2, arg3)
+			if fnErr != nil {
+				return r, fnErr
+			}
+			_cacheSetContext(ctx, c, func_name, key, r, effectiveTTL)
+			return r, nil
+		})
+		if err != nil {
+			slog.Debug("Cache miss (error, not cached):", "key", key)
+			return result, err
+		}
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
+
+func MemoizeCtx4[A, B, C, D, R any](c *Cache, ttl time.Duration, fn func(context.Context, A, B, C, D) (R, error), opts ...MemoizeOption) func(context.Context, A, B, C, D) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(ctx context.Context, arg1 A, arg2 B, arg3 C, arg4 D) (R, error) {
+		key, err := cfg.keyFunc(arg1, arg2, arg3, arg4)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(ctx, arg1, arg2, arg3, arg4)
+		}
+
+		effectiveTTL := ttl
+		if override, ok := ttlFromContext(ctx); ok {
+			effectiveTTL = override
+		}
+
+		if !bypassFromContext(ctx) {
+			if item, ts, found := _cacheGetRawContext[R](ctx, c, func_name, key); found {
+				age := time.Now().UTC().Sub(ts)
+				if age < effectiveTTL {
+					slog.Debug("Cache hit:", "key", key)
+					return item, nil
+				}
+				if maxStale, ok := staleOKFromContext(ctx); ok && age < effectiveTTL+maxStale {
+					slog.Debug("Cache hit (stale):", "key", key)
+					_refreshStaleAsync(ctx, c, func_name, key, effectiveTTL, func(rctx context.Context) (R, error) { return fn(rctx, arg1, arg2, arg3, arg4) })
+					return item, nil
+				}
+				_ = c._storeDeleteContext(ctx, func_name, key)
+			}
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(ctx, arg1, arg2, arg3, arg4)
+			if fnErr != nil {
+				return r, fnErr
+			}
+			_cacheSetContext(ctx, c, func_name, key, r, effectiveTTL)
+			return r, nil
+		})
+		if err != nil {
+			slog.Debug("Cache miss (error, not cached):", "key", key)
+			return result, err
+		}
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
+
+func MemoizeCtx5[A, B, C, D, E, R any](c *Cache, ttl time.Duration, fn func(context.Context, A, B, C, D, E) (R, error), opts ...MemoizeOption) func(context.Context, A, B, C, D, E) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(ctx context.Context, arg1 A, arg2 B, arg3 C, arg4 D, arg5 E) (R, error) {
+		key, err := cfg.keyFunc(arg1, arg2, arg3, arg4, arg5)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(ctx, arg1, arg2, arg3, arg4, arg5)
+		}
+
+		effectiveTTL := ttl
+		if override, ok := ttlFromContext(ctx); ok {
+			effectiveTTL = override
+		}
+
+		if !bypassFromContext(ctx) {
+			if item, ts, found := _cacheGetRawContext[R](ctx, c, func_name, key); found {
+				age := time.Now().UTC().Sub(ts)
+				if age < effectiveTTL {
+					slog.Debug("Cache hit:", "key", key)
+					return item, nil
+				}
+				if maxStale, ok := staleOKFromContext(ctx); ok && age < effectiveTTL+maxStale {
+					slog.Debug("Cache hit (stale):", "key", key)
+					_refreshStaleAsync(ctx, c, func_name, key, effectiveTTL, func(rctx context.Context) (R, error) { return fn(rctx, arg1, arg2, arg3, arg4, arg5) })
+					return item, nil
+				}
+				_ = c._storeDeleteContext(ctx, func_name, key)
+			}
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(ctx, arg1, arg2, arg3, arg4, arg5)
+			if fnErr != nil {
+				return r, fnErr
+			}
+			_cacheSetContext(ctx, c, func_name, key, r, effectiveTTL)
+			return r, nil
+		})
+		if err != nil {
+			slog.Debug("Cache miss (error, not cached):", "key", key)
+			return result, err
+		}
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
+
+func MemoizeCtx6[A, B, C, D, E, F, R any](c *Cache, ttl time.Duration, fn func(context.Context, A, B, C, D, E, F) (R, error), opts ...MemoizeOption) func(context.Context, A, B, C, D, E, F) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(ctx context.Context, arg1 A, arg2 B, arg3 C, arg4 D, arg5 E, arg6 F) (R, error) {
+		key, err := cfg.keyFunc(arg1, arg2, arg3, arg4, arg5, arg6)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(ctx, arg1, arg2, arg3, arg4, arg5, arg6)
+		}
+
+		effectiveTTL := ttl
+		if override, ok := ttlFromContext(ctx); ok {
+			effectiveTTL = override
+		}
+
+		if !bypassFromContext(ctx) {
+			if item, ts, found := _cacheGetRawContext[R](ctx, c, func_name, key); found {
+				age := time.Now().UTC().Sub(ts)
+				if age < effectiveTTL {
+					slog.Debug("Cache hit:", "key", key)
+					return item, nil
+				}
+				if maxStale, ok := staleOKFromContext(ctx); ok && age < effectiveTTL+maxStale {
+					slog.Debug("Cache hit (stale):", "key", key)
+					_refreshStaleAsync(ctx, c, func_name, key, effectiveTTL, func(rctx context.Context) (R, error) { return fn(rctx, arg1, arg2, arg3, arg4, arg5, arg6) })
+					return item, nil
+				}
+				_ = c._storeDeleteContext(ctx, func_name, key)
+			}
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(ctx, arg1, arg2, arg3, arg4, arg5, arg6)
+			if fnErr != nil {
+				return r, fnErr
+			}
+			_cacheSetContext(ctx, c, func_name, key, r, effectiveTTL)
+			return r, nil
+		})
+		if err != nil {
+			slog.Debug("Cache miss (error, not cached):", "key", key)
+			return result, err
+		}
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
+
+func MemoizeCtx7[A, B, C, D, E, F, G, R any](c *Cache, ttl time.Duration, fn func(context.Context, A, B, C, D, E, F, G) (R, error), opts ...MemoizeOption) func(context.Context, A, B, C, D, E, F, G) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(ctx context.Context, arg1 A, arg2 B, arg3 C, arg4 D, arg5 E, arg6 F, arg7 G) (R, error) {
+		key, err := cfg.keyFunc(arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(ctx, arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+		}
+
+		effectiveTTL := ttl
+		if override, ok := ttlFromContext(ctx); ok {
+			effectiveTTL = override
+		}
+
+		if !bypassFromContext(ctx) {
+			if item, ts, found := _cacheGetRawContext[R](ctx, c, func_name, key); found {
+				age := time.Now().UTC().Sub(ts)
+				if age < effectiveTTL {
+					slog.Debug("Cache hit:", "key", key)
+					return item, nil
+				}
+				if maxStale, ok := staleOKFromContext(ctx); ok && age < effectiveTTL+maxStale {
+					slog.Debug("Cache hit (stale):", "key", key)
+					_refreshStaleAsync(ctx, c, func_name, key, effectiveTTL, func(rctx context.Context) (R, error) { return fn(rctx, arg1, arg2, arg3, arg4, arg5, arg6, arg7) })
+					return item, nil
+				}
+				_ = c._storeDeleteContext(ctx, func_name, key)
+			}
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(ctx, arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+			if fnErr != nil {
+				return r, fnErr
+			}
+			_cacheSetContext(ctx, c, func_name, key, r, effectiveTTL)
+			return r, nil
+		})
+		if err != nil {
+			slog.Debug("Cache miss (error, not cached):", "key", key)
+			return result, err
+		}
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
+
+func MemoizeCtx8[A, B, C, D, E, F, G, H, R any](c *Cache, ttl time.Duration, fn func(context.Context, A, B, C, D, E, F, G, H) (R, error), opts ...MemoizeOption) func(context.Context, A, B, C, D, E, F, G, H) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(ctx context.Context, arg1 A, arg2 B, arg3 C, arg4 D, arg5 E, arg6 F, arg7 G, arg8 H) (R, error) {
+		key, err := cfg.keyFunc(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(ctx, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
+		}
+
+		effectiveTTL := ttl
+		if override, ok := ttlFromContext(ctx); ok {
+			effectiveTTL = override
+		}
+
+		if !bypassFromContext(ctx) {
+			if item, ts, found := _cacheGetRawContext[R](ctx, c, func_name, key); found {
+				age := time.Now().UTC().Sub(ts)
+				if age < effectiveTTL {
+					slog.Debug("Cache hit:", "key", key)
+					return item, nil
+				}
+				if maxStale, ok := staleOKFromContext(ctx); ok && age < effectiveTTL+maxStale {
+					slog.Debug("Cache hit (stale):", "key", key)
+					_refreshStaleAsync(ctx, c, func_name, key, effectiveTTL, func(rctx context.Context) (R, error) { return fn(rctx, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8) })
+					return item, nil
+				}
+				_ = c._storeDeleteContext(ctx, func_name, key)
+			}
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(ctx, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
+			if fnErr != nil {
+				return r, fnErr
+			}
+			_cacheSetContext(ctx, c, func_name, key, r, effectiveTTL)
+			return r, nil
+		})
+		if err != nil {
+			slog.Debug("Cache miss (error, not cached):", "key", key)
+			return result, err
+		}
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
+
+func MemoizeCtx9[A, B, C, D, E, F, G, H, I, R any](c *Cache, ttl time.Duration, fn func(context.Context, A, B, C, D, E, F, G, H, I) (R, error), opts ...MemoizeOption) func(context.Context, A, B, C, D, E, F, G, H, I) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(ctx context.Context, arg1 A, arg2 B, arg3 C, arg4 D, arg5 E, arg6 F, arg7 G, arg8 H, arg9 I) (R, error) {
+		key, err := cfg.keyFunc(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(ctx, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9)
+		}
+
+		effectiveTTL := ttl
+		if override, ok := ttlFromContext(ctx); ok {
+			effectiveTTL = override
+		}
+
+		if !bypassFromContext(ctx) {
+			if item, ts, found := _cacheGetRawContext[R](ctx, c, func_name, key); found {
+				age := time.Now().UTC().Sub(ts)
+				if age < effectiveTTL {
+					slog.Debug("Cache hit:", "key", key)
+					return item, nil
+				}
+				if maxStale, ok := staleOKFromContext(ctx); ok && age < effectiveTTL+maxStale {
+					slog.Debug("Cache hit (stale):", "key", key)
+					_refreshStaleAsync(ctx, c, func_name, key, effectiveTTL, func(rctx context.Context) (R, error) {
+						return fn(rctx, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9)
+					})
+					return item, nil
+				}
+				_ = c._storeDeleteContext(ctx, func_name, key)
+			}
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(ctx, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9)
+			if fnErr != nil {
+				return r, fnErr
+			}
+			_cacheSetContext(ctx, c, func_name, key, r, effectiveTTL)
+			return r, nil
+		})
+		if err != nil {
+			slog.Debug("Cache miss (error, not cached):", "key", key)
+			return result, err
+		}
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
@@ -0,0 +1,575 @@
+package persistent_cache
+
+import (
+	"encoding/gob"
+	"log/slog"
+	"time"
+)
+
+// negativeResult is a gob-safe stand-in for a memoized error: arbitrary
+// error values aren't gob-encodable, so MemoizeErr* captures just the
+// message. This mirrors the miss/not-stored sentinel pattern common to
+// Go cache adapters, just scoped to a single wrapper type instead of a
+// package-level sentinel error.
+type negativeResult struct {
+	Message string
+}
+
+func (e *negativeResult) Error() string { return e.Message }
+
+func init() {
+	gob.Register(&negativeResult{})
+}
+
+// errEnvelope is what MemoizeErr* actually serializes, so both the
+// success and failure branches survive a restart.
+type errEnvelope[R any] struct {
+	Value   R
+	IsError bool
+	Err     *negativeResult
+}
+
+// _cacheGetEnvelope fetches a raw errEnvelope without judging expiry;
+// MemoizeErr* applies ttl or negativeTTL itself once it knows which
+// branch the envelope holds.
+func _cacheGetEnvelope[R any](c *Cache, funcName, key string) (errEnvelope[R], time.Time, bool) {
+	var zero errEnvelope[R]
+
+	data, ts, found := c._storeGet(funcName, key)
+	if !found {
+		return zero, time.Time{}, false
+	}
+
+	env, err := _deserialize[errEnvelope[R]](data)
+	if err != nil {
+		slog.Debug("Deserialization error:", "error", err)
+		return zero, time.Time{}, false
+	}
+
+	return env, ts, true
+}
+
+// MemoizeErr0 wraps fn so its result is cached in c (or the package-level
+// default Cache, if c is nil). Successful results are cached under ttl;
+// errors are cached separately under negativeTTL (0 = never cache errors)
+// so a transient failure doesn't stick. Concurrent callers computing the
+// same key share one execution of fn unless c disables singleflight.
+func MemoizeErr0[R any](c *Cache, ttl time.Duration, negativeTTL time.Duration, fn func() (R, error), opts ...MemoizeOption) func() (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func() (R, error) {
+		key, keyErr := cfg.keyFunc()
+		if keyErr != nil {
+			slog.Debug("Key generation error", "error", keyErr)
+			return fn()
+		}
+
+		if env, ts, found := _cacheGetEnvelope[R](c, func_name, key); found {
+			effectiveTTL := ttl
+			if env.IsError {
+				effectiveTTL = negativeTTL
+			}
+			if effectiveTTL > 0 && time.Now().UTC().Before(ts.Add(effectiveTTL)) {
+				if env.IsError {
+					slog.Debug("Cache hit (error):", "key", key)
+					return env.Value, env.Err
+				}
+				slog.Debug("Cache hit:", "key", key)
+				return env.Value, nil
+			}
+			_ = c._storeDelete(func_name, key)
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn()
+			if fnErr != nil {
+				if negativeTTL > 0 {
+					_cacheSet(c, func_name, key, errEnvelope[R]{Value: r, IsError: true, Err: &negativeResult{Message: fnErr.Error()}}, negativeTTL)
+				}
+				return r, fnErr
+			}
+			_cacheSet(c, func_name, key, errEnvelope[R]{Value: r}, ttl)
+			return r, nil
+		})
+		if err != nil {
+			if negativeTTL <= 0 {
+				slog.Debug("Cache miss (error, not cached):", "key", key)
+				return result, err
+			}
+			slog.Debug("Cache miss (error):", "key", key)
+			return result, err
+		}
+
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
+
+func MemoizeErr1[A, R any](c *Cache, ttl time.Duration, negativeTTL time.Duration, fn func(A) (R, error), opts ...MemoizeOption) func(A) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(arg A) (R, error) {
+		key, keyErr := cfg.keyFunc(arg)
+		if keyErr != nil {
+			slog.Debug("Key generation error", "error", keyErr)
+			return fn(arg)
+		}
+
+		if env, ts, found := _cacheGetEnvelope[R](c, func_name, key); found {
+			effectiveTTL := ttl
+			if env.IsError {
+				effectiveTTL = negativeTTL
+			}
+			if effectiveTTL > 0 && time.Now().UTC().Before(ts.Add(effectiveTTL)) {
+				if env.IsError {
+					slog.Debug("Cache hit (error):", "key", key)
+					return env.Value, env.Err
+				}
+				slog.Debug("Cache hit:", "key", key)
+				return env.Value, nil
+			}
+			_ = c._storeDelete(func_name, key)
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(arg)
+			if fnErr != nil {
+				if negativeTTL > 0 {
+					_cacheSet(c, func_name, key, errEnvelope[R]{Value: r, IsError: true, Err: &negativeResult{Message: fnErr.Error()}}, negativeTTL)
+				}
+				return r, fnErr
+			}
+			_cacheSet(c, func_name, key, errEnvelope[R]{Value: r}, ttl)
+			return r, nil
+		})
+		if err != nil {
+			if negativeTTL <= 0 {
+				slog.Debug("Cache miss (error, not cached):", "key", key)
+				return result, err
+			}
+			slog.Debug("Cache miss (error):", "key", key)
+			return result, err
+		}
+
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
+
+func MemoizeErr2[A, B, R any](c *Cache, ttl time.Duration, negativeTTL time.Duration, fn func(A, B) (R, error), opts ...MemoizeOption) func(A, B) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(arg1 A, arg2 B) (R, error) {
+		key, keyErr := cfg.keyFunc(arg1, arg2)
+		if keyErr != nil {
+			slog.Debug("Key generation error", "error", keyErr)
+			return fn(arg1, arg2)
+		}
+
+		if env, ts, found := _cacheGetEnvelope[R](c, func_name, key); found {
+			effectiveTTL := ttl
+			if env.IsError {
+				effectiveTTL = negativeTTL
+			}
+			if effectiveTTL > 0 && time.Now().UTC().Before(ts.Add(effectiveTTL)) {
+				if env.IsError {
+					slog.Debug("Cache hit (error):", "key", key)
+					return env.Value, env.Err
+				}
+				slog.Debug("Cache hit:", "key", key)
+				return env.Value, nil
+			}
+			_ = c._storeDelete(func_name, key)
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(arg1, arg2)
+			if fnErr != nil {
+				if negativeTTL > 0 {
+					_cacheSet(c, func_name, key, errEnvelope[R]{Value: r, IsError: true, Err: &negativeResult{Message: fnErr.Error()}}, negativeTTL)
+				}
+				return r, fnErr
+			}
+			_cacheSet(c, func_name, key, errEnvelope[R]{Value: r}, ttl)
+			return r, nil
+		})
+		if err != nil {
+			if negativeTTL <= 0 {
+				slog.Debug("Cache miss (error, not cached):", "key", key)
+				return result, err
+			}
+			slog.Debug("Cache miss (error):", "key", key)
+			return result, err
+		}
+
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
+
+func MemoizeErr3[A, B, C, R any](c *Cache, ttl time.Duration, negativeTTL time.Duration, fn func(A, B, C) (R, error), opts ...MemoizeOption) func(A, B, C) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(arg1 A, arg2 B, arg3 C) (R, error) {
+		key, keyErr := cfg.keyFunc(arg1, arg2, arg3)
+		if keyErr != nil {
+			slog.Debug("Key generation error", "error", keyErr)
+			return fn(arg1, arg2, arg3)
+		}
+
+		if env, ts, found := _cacheGetEnvelope[R](c, func_name, key); found {
+			effectiveTTL := ttl
+			if env.IsError {
+				effectiveTTL = negativeTTL
+			}
+			if effectiveTTL > 0 && time.Now().UTC().Before(ts.Add(effectiveTTL)) {
+				if env.IsError {
+					slog.Debug("Cache hit (error):", "key", key)
+					return env.Value, env.Err
+				}
+				slog.Debug("Cache hit:", "key", key)
+				return env.Value, nil
+			}
+			_ = c._storeDelete(func_name, key)
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(arg1, arg2, arg3)
+			if fnErr != nil {
+				if negativeTTL > 0 {
+					_cacheSet(c, func_name, key, errEnvelope[R]{Value: r, IsError: true, Err: &negativeResult{Message: fnErr.Error()}}, negativeTTL)
+				}
+				return r, fnErr
+			}
+			_cacheSet(c, func_name, key, errEnvelope[R]{Value: r}, ttl)
+			return r, nil
+		})
+		if err != nil {
+			if negativeTTL <= 0 {
+				slog.Debug("Cache miss (error, not cached):", "key", key)
+				return result, err
+			}
+			slog.Debug("Cache miss (error):", "key", key)
+			return result, err
+		}
+
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
+
+func MemoizeErr4[A, B, C, D, R any](c *Cache, ttl time.Duration, negativeTTL time.Duration, fn func(A, B, C, D) (R, error), opts ...MemoizeOption) func(A, B, C, D) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(arg1 A, arg2 B, arg3 C, arg4 D) (R, error) {
+		key, keyErr := cfg.keyFunc(arg1, arg2, arg3, arg4)
+		if keyErr != nil {
+			slog.Debug("Key generation error", "error", keyErr)
+			return fn(arg1, arg2, arg3, arg4)
+		}
+
+		if env, ts, found := _cacheGetEnvelope[R](c, func_name, key); found {
+			effectiveTTL := ttl
+			if env.IsError {
+				effectiveTTL = negativeTTL
+			}
+			if effectiveTTL > 0 && time.Now().UTC().Before(ts.Add(effectiveTTL)) {
+				if env.IsError {
+					slog.Debug("Cache hit (error):", "key", key)
+					return env.Value, env.Err
+				}
+				slog.Debug("Cache hit:", "key", key)
+				return env.Value, nil
+			}
+			_ = c._storeDelete(func_name, key)
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(arg1, arg2, arg3, arg4)
+			if fnErr != nil {
+				if negativeTTL > 0 {
+					_cacheSet(c, func_name, key, errEnvelope[R]{Value: r, IsError: true, Err: &negativeResult{Message: fnErr.Error()}}, negativeTTL)
+				}
+				return r, fnErr
+			}
+			_cacheSet(c, func_name, key, errEnvelope[R]{Value: r}, ttl)
+			return r, nil
+		})
+		if err != nil {
+			if negativeTTL <= 0 {
+				slog.Debug("Cache miss (error, not cached):", "key", key)
+				return result, err
+			}
+			slog.Debug("Cache miss (error):", "key", key)
+			return result, err
+		}
+
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
+
+func MemoizeErr5[A, B, C, D, E, R any](c *Cache, ttl time.Duration, negativeTTL time.Duration, fn func(A, B, C, D, E) (R, error), opts ...MemoizeOption) func(A, B, C, D, E) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(arg1 A, arg2 B, arg3 C, arg4 D, arg5 E) (R, error) {
+		key, keyErr := cfg.keyFunc(arg1, arg2, arg3, arg4, arg5)
+		if keyErr != nil {
+			slog.Debug("Key generation error", "error", keyErr)
+			return fn(arg1, arg2, arg3, arg4, arg5)
+		}
+
+		if env, ts, found := _cacheGetEnvelope[R](c, func_name, key); found {
+			effectiveTTL := ttl
+			if env.IsError {
+				effectiveTTL = negativeTTL
+			}
+			if effectiveTTL > 0 && time.Now().UTC().Before(ts.Add(effectiveTTL)) {
+				if env.IsError {
+					slog.Debug("Cache hit (error):", "key", key)
+					return env.Value, env.Err
+				}
+				slog.Debug("Cache hit:", "key", key)
+				return env.Value, nil
+			}
+			_ = c._storeDelete(func_name, key)
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(arg1, arg2, arg3, arg4, arg5)
+			if fnErr != nil {
+				if negativeTTL > 0 {
+					_cacheSet(c, func_name, key, errEnvelope[R]{Value: r, IsError: true, Err: &negativeResult{Message: fnErr.Error()}}, negativeTTL)
+				}
+				return r, fnErr
+			}
+			_cacheSet(c, func_name, key, errEnvelope[R]{Value: r}, ttl)
+			return r, nil
+		})
+		if err != nil {
+			if negativeTTL <= 0 {
+				slog.Debug("Cache miss (error, not cached):", "key", key)
+				return result, err
+			}
+			slog.Debug("Cache miss (error):", "key", key)
+			return result, err
+		}
+
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
+
+func MemoizeErr6[A, B, C, D, E, F, R any](c *Cache, ttl time.Duration, negativeTTL time.Duration, fn func(A, B, C, D, E, F) (R, error), opts ...MemoizeOption) func(A, B, C, D, E, F) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(arg1 A, arg2 B, arg3 C, arg4 D, arg5 E, arg6 F) (R, error) {
+		key, keyErr := cfg.keyFunc(arg1, arg2, arg3, arg4, arg5, arg6)
+		if keyErr != nil {
+			slog.Debug("Key generation error", "error", keyErr)
+			return fn(arg1, arg2, arg3, arg4, arg5, arg6)
+		}
+
+		if env, ts, found := _cacheGetEnvelope[R](c, func_name, key); found {
+			effectiveTTL := ttl
+			if env.IsError {
+				effectiveTTL = negativeTTL
+			}
+			if effectiveTTL > 0 && time.Now().UTC().Before(ts.Add(effectiveTTL)) {
+				if env.IsError {
+					slog.Debug("Cache hit (error):", "key", key)
+					return env.Value, env.Err
+				}
+				slog.Debug("Cache hit:", "key", key)
+				return env.Value, nil
+			}
+			_ = c._storeDelete(func_name, key)
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(arg1, arg2, arg3, arg4, arg5, arg6)
+			if fnErr != nil {
+				if negativeTTL > 0 {
+					_cacheSet(c, func_name, key, errEnvelope[R]{Value: r, IsError: true, Err: &negativeResult{Message: fnErr.Error()}}, negativeTTL)
+				}
+				return r, fnErr
+			}
+			_cacheSet(c, func_name, key, errEnvelope[R]{Value: r}, ttl)
+			return r, nil
+		})
+		if err != nil {
+			if negativeTTL <= 0 {
+				slog.Debug("Cache miss (error, not cached):", "key", key)
+				return result, err
+			}
+			slog.Debug("Cache miss (error):", "key", key)
+			return result, err
+		}
+
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
+
+func MemoizeErr7[A, B, C, D, E, F, G, R any](c *Cache, ttl time.Duration, negativeTTL time.Duration, fn func(A, B, C, D, E, F, G) (R, error), opts ...MemoizeOption) func(A, B, C, D, E, F, G) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(arg1 A, arg2 B, arg3 C, arg4 D, arg5 E, arg6 F, arg7 G) (R, error) {
+		key, keyErr := cfg.keyFunc(arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+		if keyErr != nil {
+			slog.Debug("Key generation error", "error", keyErr)
+			return fn(arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+		}
+
+		if env, ts, found := _cacheGetEnvelope[R](c, func_name, key); found {
+			effectiveTTL := ttl
+			if env.IsError {
+				effectiveTTL = negativeTTL
+			}
+			if effectiveTTL > 0 && time.Now().UTC().Before(ts.Add(effectiveTTL)) {
+				if env.IsError {
+					slog.Debug("Cache hit (error):", "key", key)
+					return env.Value, env.Err
+				}
+				slog.Debug("Cache hit:", "key", key)
+				return env.Value, nil
+			}
+			_ = c._storeDelete(func_name, key)
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+			if fnErr != nil {
+				if negativeTTL > 0 {
+					_cacheSet(c, func_name, key, errEnvelope[R]{Value: r, IsError: true, Err: &negativeResult{Message: fnErr.Error()}}, negativeTTL)
+				}
+				return r, fnErr
+			}
+			_cacheSet(c, func_name, key, errEnvelope[R]{Value: r}, ttl)
+			return r, nil
+		})
+		if err != nil {
+			if negativeTTL <= 0 {
+				slog.Debug("Cache miss (error, not cached):", "key", key)
+				return result, err
+			}
+			slog.Debug("Cache miss (error):", "key", key)
+			return result, err
+		}
+
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
+
+func MemoizeErr8[A, B, C, D, E, F, G, H, R any](c *Cache, ttl time.Duration, negativeTTL time.Duration, fn func(A, B, C, D, E, F, G, H) (R, error), opts ...MemoizeOption) func(A, B, C, D, E, F, G, H) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(arg1 A, arg2 B, arg3 C, arg4 D, arg5 E, arg6 F, arg7 G, arg8 H) (R, error) {
+		key, keyErr := cfg.keyFunc(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
+		if keyErr != nil {
+			slog.Debug("Key generation error", "error", keyErr)
+			return fn(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
+		}
+
+		if env, ts, found := _cacheGetEnvelope[R](c, func_name, key); found {
+			effectiveTTL := ttl
+			if env.IsError {
+				effectiveTTL = negativeTTL
+			}
+			if effectiveTTL > 0 && time.Now().UTC().Before(ts.Add(effectiveTTL)) {
+				if env.IsError {
+					slog.Debug("Cache hit (error):", "key", key)
+					return env.Value, env.Err
+				}
+				slog.Debug("Cache hit:", "key", key)
+				return env.Value, nil
+			}
+			_ = c._storeDelete(func_name, key)
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
+			if fnErr != nil {
+				if negativeTTL > 0 {
+					_cacheSet(c, func_name, key, errEnvelope[R]{Value: r, IsError: true, Err: &negativeResult{Message: fnErr.Error()}}, negativeTTL)
+				}
+				return r, fnErr
+			}
+			_cacheSet(c, func_name, key, errEnvelope[R]{Value: r}, ttl)
+			return r, nil
+		})
+		if err != nil {
+			if negativeTTL <= 0 {
+				slog.Debug("Cache miss (error, not cached):", "key", key)
+				return result, err
+			}
+			slog.Debug("Cache miss (error):", "key", key)
+			return result, err
+		}
+
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
+
+func MemoizeErr9[A, B, C, D, E, F, G, H, I, R any](c *Cache, ttl time.Duration, negativeTTL time.Duration, fn func(A, B, C, D, E, F, G, H, I) (R, error), opts ...MemoizeOption) func(A, B, C, D, E, F, G, H, I) (R, error) {
+	c = _orDefault(c)
+	cfg := _newMemoizeConfig(opts)
+	func_name := _funcName(fn)
+	return func(arg1 A, arg2 B, arg3 C, arg4 D, arg5 E, arg6 F, arg7 G, arg8 H, arg9 I) (R, error) {
+		key, keyErr := cfg.keyFunc(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9)
+		if keyErr != nil {
+			slog.Debug("Key generation error", "error", keyErr)
+			return fn(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9)
+		}
+
+		if env, ts, found := _cacheGetEnvelope[R](c, func_name, key); found {
+			effectiveTTL := ttl
+			if env.IsError {
+				effectiveTTL = negativeTTL
+			}
+			if effectiveTTL > 0 && time.Now().UTC().Before(ts.Add(effectiveTTL)) {
+				if env.IsError {
+					slog.Debug("Cache hit (error):", "key", key)
+					return env.Value, env.Err
+				}
+				slog.Debug("Cache hit:", "key", key)
+				return env.Value, nil
+			}
+			_ = c._storeDelete(func_name, key)
+		}
+
+		result, err := _callMemoizedErr(c, func_name, key, func() (R, error) {
+			r, fnErr := fn(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9)
+			if fnErr != nil {
+				if negativeTTL > 0 {
+					_cacheSet(c, func_name, key, errEnvelope[R]{Value: r, IsError: true, Err: &negativeResult{Message: fnErr.Error()}}, negativeTTL)
+				}
+				return r, fnErr
+			}
+			_cacheSet(c, func_name, key, errEnvelope[R]{Value: r}, ttl)
+			return r, nil
+		})
+		if err != nil {
+			if negativeTTL <= 0 {
+				slog.Debug("Cache miss (error, not cached):", "key", key)
+				return result, err
+			}
+			slog.Debug("Cache miss (error):", "key", key)
+			return result, err
+		}
+
+		slog.Debug("Cache miss:", "key", key)
+		return result, nil
+	}
+}
@@ -0,0 +1,80 @@
+package persistent_cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestCache(t *testing.T) *Cache {
+	t.Helper()
+	c, err := NewCache("memory", "")
+	if err != nil {
+		t.Fatalf("NewCache: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+// TestMemoizeErr0NegativeTTL checks that a failing call is re-run once its
+// NegativeTTL has elapsed, but served from cache (without re-running fn)
+// while still within it.
+func TestMemoizeErr0NegativeTTL(t *testing.T) {
+	c := newTestCache(t)
+	calls := 0
+	fn := MemoizeErr0(c, time.Hour, 20*time.Millisecond, func() (string, error) {
+		calls++
+		return "", errors.New("boom")
+	})
+
+	if _, err := fn(); err == nil {
+		t.Fatal("expected error from first call")
+	}
+	if _, err := fn(); err == nil || calls != 1 {
+		t.Fatalf("expected cached error without a second call, calls = %d", calls)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if _, err := fn(); err == nil || calls != 2 {
+		t.Fatalf("expected fn to re-run after NegativeTTL elapsed, calls = %d", calls)
+	}
+}
+
+// TestMemoizeErr0NegativeTTLDisabled checks the default NegativeTTL of 0
+// never caches an error, so every call re-runs fn.
+func TestMemoizeErr0NegativeTTLDisabled(t *testing.T) {
+	c := newTestCache(t)
+	calls := 0
+	fn := MemoizeErr0(c, time.Hour, 0, func() (string, error) {
+		calls++
+		return "", errors.New("boom")
+	})
+
+	_, _ = fn()
+	_, _ = fn()
+
+	if calls != 2 {
+		t.Fatalf("calls = %d, want 2 (errors should never be cached)", calls)
+	}
+}
+
+// TestMemoizeErr0SuccessCached checks a successful result is still cached
+// under the normal ttl, independent of NegativeTTL.
+func TestMemoizeErr0SuccessCached(t *testing.T) {
+	c := newTestCache(t)
+	calls := 0
+	fn := MemoizeErr0(c, time.Hour, 0, func() (string, error) {
+		calls++
+		return "ok", nil
+	})
+
+	v1, err := fn()
+	if err != nil || v1 != "ok" {
+		t.Fatalf("fn() = %q, %v", v1, err)
+	}
+	v2, err := fn()
+	if err != nil || v2 != "ok" || calls != 1 {
+		t.Fatalf("fn() = %q, %v, calls = %d, want cached result", v2, err, calls)
+	}
+}
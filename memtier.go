@@ -0,0 +1,94 @@
+package persistent_cache
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// memTier is the in-process LRU layered in front of a Cache's Store (see
+// CacheOptions.MemoryEntries/MemoryBytes). It caches raw serialized values
+// so _cacheGet/_cacheSet don't care whether a hit came from memory or the
+// Store underneath.
+type memTier struct {
+	mu       sync.Mutex
+	cache    *lru.Cache[string, memTierEntry]
+	maxBytes int64
+	bytes    int64
+}
+
+type memTierEntry struct {
+	value []byte
+	ts    time.Time
+	size  int64
+}
+
+func newMemTier(maxEntries int, maxBytes int64) *memTier {
+	if maxEntries <= 0 {
+		maxEntries = 1
+	}
+	m := &memTier{maxBytes: maxBytes}
+	// onEvicted fires for every removal the LRU makes on its own (capacity
+	// eviction from Add, and explicit Remove/RemoveOldest), always under
+	// m.mu since that's the only place these are called from. It must not
+	// re-lock m.mu.
+	cache, _ := lru.NewWithEvict[string, memTierEntry](maxEntries, func(_ string, evicted memTierEntry) {
+		m.bytes -= evicted.size
+	})
+	m.cache = cache
+	return m
+}
+
+func memTierKey(funcName, key string) string {
+	return funcName + "\x00" + key
+}
+
+func (m *memTier) get(funcName, key string) ([]byte, time.Time, bool) {
+	entry, ok := m.cache.Get(memTierKey(funcName, key))
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	return entry.value, entry.ts, true
+}
+
+func (m *memTier) set(funcName, key string, value []byte, ts time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := memTierKey(funcName, key)
+	if old, ok := m.cache.Peek(k); ok {
+		m.bytes -= old.size
+	}
+
+	size := int64(len(value))
+	m.cache.Add(k, memTierEntry{value: value, ts: ts, size: size})
+	m.bytes += size
+
+	if m.maxBytes <= 0 {
+		return
+	}
+	for m.bytes > m.maxBytes {
+		if _, _, ok := m.cache.RemoveOldest(); !ok {
+			break
+		}
+	}
+}
+
+func (m *memTier) delete(funcName, key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cache.Remove(memTierKey(funcName, key))
+}
+
+func (m *memTier) deleteFunc(funcName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prefix := funcName + "\x00"
+	for _, k := range m.cache.Keys() {
+		if strings.HasPrefix(k, prefix) {
+			m.cache.Remove(k)
+		}
+	}
+}
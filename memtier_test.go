@@ -0,0 +1,63 @@
+package persistent_cache
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMemTierByteEvictionAccounting guards against the byte counter
+// drifting upward when the LRU evicts entries on its own (on Add, once
+// maxEntries is exceeded), which used to go untracked and made the byte
+// cap evict far more aggressively than intended.
+func TestMemTierByteEvictionAccounting(t *testing.T) {
+	m := newMemTier(10, 0)
+	now := time.Now()
+
+	for i := 0; i < 20; i++ {
+		m.set("fn", string(rune('a'+i)), []byte("xxxxxxxxxx"), now)
+	}
+
+	if got, want := m.bytes, int64(10*len("xxxxxxxxxx")); got != want {
+		t.Fatalf("bytes = %d after %d evictions past maxEntries, want %d", got, 10, want)
+	}
+}
+
+// TestMemTierDeleteAccounting guards against delete/deleteFunc double- or
+// under-counting bytes now that removal also flows through the LRU's evict
+// callback.
+func TestMemTierDeleteAccounting(t *testing.T) {
+	m := newMemTier(10, 0)
+	now := time.Now()
+
+	m.set("fn", "a", []byte("12345"), now)
+	m.set("fn", "b", []byte("1234567890"), now)
+
+	m.delete("fn", "a")
+	if got, want := m.bytes, int64(10); got != want {
+		t.Fatalf("bytes = %d after delete, want %d", got, want)
+	}
+
+	m.deleteFunc("fn")
+	if got, want := m.bytes, int64(0); got != want {
+		t.Fatalf("bytes = %d after deleteFunc, want %d", got, want)
+	}
+}
+
+// TestMemTierByteCap exercises the additional MemoryBytes cap: once the
+// total serialized size exceeds it, the oldest entries are evicted even
+// though maxEntries hasn't been reached.
+func TestMemTierByteCap(t *testing.T) {
+	m := newMemTier(100, 10)
+	now := time.Now()
+
+	m.set("fn", "a", []byte("12345"), now)
+	m.set("fn", "b", []byte("12345"), now)
+	m.set("fn", "c", []byte("12345"), now)
+
+	if _, _, ok := m.get("fn", "a"); ok {
+		t.Fatal("oldest entry should have been evicted by the byte cap")
+	}
+	if m.bytes > 10 {
+		t.Fatalf("bytes = %d, want <= 10", m.bytes)
+	}
+}
@@ -2,7 +2,7 @@ package persistent_cache
 
 import (
 	"bytes"
-	"database/sql"
+	"context"
 	"encoding/gob"
 	"fmt"
 	"log"
@@ -12,40 +12,130 @@ import (
 	"sync"
 	"time"
 
-	_ "modernc.org/sqlite" // SQLite driver
+	"golang.org/x/sync/singleflight"
 )
 
-// Cache handles SQLite-based persistent caching with Gob encoding
+// Cache memoizes function results through a pluggable Store backend, with
+// an optional in-memory LRU tier and singleflight stampede protection in
+// front of it (see CacheOptions).
 type Cache struct {
-	db *sql.DB
+	store Store
+	mem   *memTier            // nil if the memory tier is disabled
+	sf    *singleflight.Group // nil if singleflight is disabled
+
+	// OnEvicted, if set, is called by the background janitor (see
+	// StartJanitor) for every entry it purges because it expired.
+	OnEvicted func(funcName, key string)
+
+	janitor *janitor
 }
 
-// _newCache initializes the cache database and table
-func _newCache(dbFile string) *Cache {
-	db, err := sql.Open("sqlite", dbFile)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Create table for caching function results
-	_, err = db.Exec(`
-	CREATE TABLE IF NOT EXISTS cache (
-		id INTEGER PRIMARY KEY,
-		function TEXT,
-		args BLOB,
-		result BLOB,
-		timestamp INTEGER DEFAULT (strftime('%s', 'now'))
-	);`)
+// CacheOptions tunes the tiers layered in front of a Cache's Store.
+type CacheOptions struct {
+	// MemoryEntries caps the number of entries kept in the in-process LRU
+	// in front of the Store. 0 (the default) disables the memory tier.
+	MemoryEntries int
+	// MemoryBytes additionally caps the memory tier by total serialized
+	// value size. 0 means no byte cap; it only applies when MemoryEntries
+	// is also set.
+	MemoryBytes int64
+	// DisableSingleflight turns off request coalescing, so concurrent
+	// callers computing the same key each run fn instead of sharing one
+	// result.
+	DisableSingleflight bool
+}
+
+// NewCache constructs a Cache backed by the named adapter (see Register).
+// config is adapter-specific: a SQLite file path for "sqlite", a Redis DSN
+// for "redis", or ignored for "memory". opts, if given, tunes the memory
+// and singleflight tiers layered in front of the Store.
+func NewCache(adapterName, config string, opts ...CacheOptions) (*Cache, error) {
+	adaptersMu.RLock()
+	factory, ok := adapters[adapterName]
+	adaptersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("persistent_cache: unknown adapter %q (forgot to import it?)", adapterName)
+	}
+	store, err := factory(config)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("persistent_cache: adapter %q: %w", adapterName, err)
+	}
+
+	var o CacheOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	c := &Cache{store: store}
+	if o.MemoryEntries > 0 {
+		c.mem = newMemTier(o.MemoryEntries, o.MemoryBytes)
+	}
+	if !o.DisableSingleflight {
+		c.sf = new(singleflight.Group)
 	}
-	return &Cache{db}
+	return c, nil
+}
+
+// Close stops c's janitor, if running, and releases the resources held by
+// c's underlying Store.
+func (c *Cache) Close() error {
+	c.StopJanitor()
+	return c.store.Close()
+}
+
+// DeleteFuncCache removes every entry cached for fn from c (including its
+// memory tier, if enabled), so multiple Cache instances backed by
+// different stores can be purged independently.
+func (c *Cache) DeleteFuncCache(fn any) error {
+	return c._storeDeleteFunc(_funcName(fn))
+}
+
+// StartJanitor spawns a background goroutine that sweeps expired entries
+// from c's store every cleanupInterval, firing OnEvicted for each one it
+// removes. It is a no-op if the store doesn't implement Sweeper (stores
+// that expire entries natively, like the redis adapter, don't need one).
+// Call StopJanitor, or Close, to stop it; a finalizer stops it too if c is
+// garbage collected without either being called.
+func (c *Cache) StartJanitor(cleanupInterval time.Duration) {
+	sweeper, ok := c.store.(Sweeper)
+	if !ok {
+		slog.Debug("persistent_cache: store does not support janitor sweeps", "store", fmt.Sprintf("%T", c.store))
+		return
+	}
+	if c.janitor != nil {
+		c.StopJanitor()
+	}
+	j := &janitor{interval: cleanupInterval, stop: make(chan struct{})}
+	c.janitor = j
+	mem := c.mem
+	onEvicted := c.OnEvicted
+	go j.run(sweeper, func(funcName, key string) {
+		if mem != nil {
+			mem.delete(funcName, key)
+		}
+		if onEvicted != nil {
+			onEvicted(funcName, key)
+		}
+	})
+	runtime.SetFinalizer(c, (*Cache).StopJanitor)
+}
+
+// StopJanitor stops the background janitor started by StartJanitor. It is
+// a no-op if no janitor is running.
+func (c *Cache) StopJanitor() {
+	if c.janitor == nil {
+		return
+	}
+	close(c.janitor.stop)
+	c.janitor = nil
 }
 
 var lock = &sync.Mutex{}
 
 var _singleInstance *Cache
 
+// _getPersistentCache returns the package-level default Cache used by the
+// Memoize* helpers when called with a nil *Cache, creating it on first use.
 func _getPersistentCache() *Cache {
 	if _singleInstance == nil {
 		lock.Lock()
@@ -53,12 +143,120 @@ func _getPersistentCache() *Cache {
 		if _singleInstance == nil {
 			slog.Debug("Creating single instance now.")
 			// TODO: Make the cache file path configurable
-			_singleInstance = _newCache("cache.db")
+			c, err := NewCache("sqlite", "cache.db")
+			if err != nil {
+				log.Fatal(err)
+			}
+			_singleInstance = c
 		}
 	}
 	return _singleInstance
 }
 
+// _orDefault returns c, or the package-level default Cache if c is nil.
+func _orDefault(c *Cache) *Cache {
+	if c != nil {
+		return c
+	}
+	return _getPersistentCache()
+}
+
+// _storeGet consults c's memory tier before falling back to its Store,
+// promoting Store hits back into the memory tier.
+func (c *Cache) _storeGet(funcName, key string) ([]byte, time.Time, bool) {
+	if c.mem != nil {
+		if data, ts, ok := c.mem.get(funcName, key); ok {
+			return data, ts, true
+		}
+	}
+
+	data, ts, err := c.store.Get(funcName, key)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+	if c.mem != nil {
+		c.mem.set(funcName, key, data, ts)
+	}
+	return data, ts, true
+}
+
+// _storeSet writes data through to c's memory tier (if enabled) and its
+// Store.
+func (c *Cache) _storeSet(funcName, key string, data []byte, ts time.Time, ttl time.Duration) error {
+	if c.mem != nil {
+		c.mem.set(funcName, key, data, ts)
+	}
+	return c.store.Set(funcName, key, data, ts, ttl)
+}
+
+// _storeDelete removes a single entry from c's memory tier (if enabled)
+// and its Store.
+func (c *Cache) _storeDelete(funcName, key string) error {
+	if c.mem != nil {
+		c.mem.delete(funcName, key)
+	}
+	return c.store.Delete(funcName, key)
+}
+
+// _storeDeleteFunc removes every entry cached for funcName from c's
+// memory tier (if enabled) and its Store.
+func (c *Cache) _storeDeleteFunc(funcName string) error {
+	if c.mem != nil {
+		c.mem.deleteFunc(funcName)
+	}
+	return c.store.DeleteFunc(funcName)
+}
+
+// _storeGetContext is _storeGet with ctx threaded through to a ContextStore,
+// for stores that can honor cancellation/deadlines (see MemoizeCtx*). It
+// falls back to the plain Get for stores that don't implement ContextStore.
+func (c *Cache) _storeGetContext(ctx context.Context, funcName, key string) ([]byte, time.Time, bool) {
+	if c.mem != nil {
+		if data, ts, ok := c.mem.get(funcName, key); ok {
+			return data, ts, true
+		}
+	}
+
+	var data []byte
+	var ts time.Time
+	var err error
+	if cs, ok := c.store.(ContextStore); ok {
+		data, ts, err = cs.GetContext(ctx, funcName, key)
+	} else {
+		data, ts, err = c.store.Get(funcName, key)
+	}
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+	if c.mem != nil {
+		c.mem.set(funcName, key, data, ts)
+	}
+	return data, ts, true
+}
+
+// _storeSetContext is _storeSet with ctx threaded through to a ContextStore.
+func (c *Cache) _storeSetContext(ctx context.Context, funcName, key string, data []byte, ts time.Time, ttl time.Duration) error {
+	if c.mem != nil {
+		c.mem.set(funcName, key, data, ts)
+	}
+	if cs, ok := c.store.(ContextStore); ok {
+		return cs.SetContext(ctx, funcName, key, data, ts, ttl)
+	}
+	return c.store.Set(funcName, key, data, ts, ttl)
+}
+
+// _storeDeleteContext is _storeDelete with ctx threaded through to a
+// ContextStore.
+func (c *Cache) _storeDeleteContext(ctx context.Context, funcName, key string) error {
+	if c.mem != nil {
+		c.mem.delete(funcName, key)
+	}
+	if cs, ok := c.store.(ContextStore); ok {
+		return cs.DeleteContext(ctx, funcName, key)
+	}
+	return c.store.Delete(funcName, key)
+}
+
 // _serialize encodes a Go object using Gob
 func _serialize[T any](value T) ([]byte, error) {
 	var buffer bytes.Buffer
@@ -67,17 +265,16 @@ func _serialize[T any](value T) ([]byte, error) {
 	return buffer.Bytes(), err
 }
 
-// _cacheSet stores a value in the cache with expiration
-func _cacheSet[T any](func_name string, key string, value T) {
+// _cacheSet stores a value in the cache, recording ttl so a janitor can
+// later evaluate expiry without the caller present.
+func _cacheSet[T any](c *Cache, func_name string, key string, value T, ttl time.Duration) {
 	serializedValue, err := _serialize(value)
 	if err != nil {
 		slog.Debug("Serialization error", "error", err)
 		return
 	}
 
-	c := _getPersistentCache()
-	_, err = c.db.Exec("INSERT INTO cache (function, args, result) VALUES (?, ?, ?);", func_name, key, serializedValue)
-	if err != nil {
+	if err := c._storeSet(func_name, key, serializedValue, time.Now().UTC(), ttl); err != nil {
 		slog.Debug("Cache Set Error:", "error", err)
 	}
 }
@@ -92,26 +289,20 @@ func _deserialize[T any](data []byte) (T, error) {
 }
 
 // _cacheGet retrieves a value from the cache and deserializes it
-func _cacheGet[T any](func_name string, key string, ttl time.Duration) (T, bool) {
-	var data []byte
-	var timestamp int64
-
+func _cacheGet[T any](c *Cache, func_name string, key string, ttl time.Duration) (T, bool) {
 	var zero T
 
-	c := _getPersistentCache()
-	err := c.db.
-		QueryRow("SELECT result, timestamp FROM cache WHERE function = ? AND args = ?", func_name, key).
-		Scan(&data, &timestamp)
-	if err != nil {
+	data, timestamp, found := c._storeGet(func_name, key)
+	if !found {
 		return zero, false
 	}
 
-	expirationTime := time.Unix(timestamp, 0).UTC().Add(ttl)
+	expirationTime := timestamp.Add(ttl)
 	currentTime := time.Now().UTC()
 
 	// Check if expired
 	if !currentTime.Before(expirationTime) {
-		_, _ = c.db.Exec("DELETE FROM cache WHERE function = ? AND args = ?", func_name, key)
+		_ = c._storeDelete(func_name, key)
 		return zero, false
 	}
 
@@ -124,218 +315,342 @@ func _cacheGet[T any](func_name string, key string, ttl time.Duration) (T, bool)
 	return item, true
 }
 
-func _funcName(fn any) string {
-	return runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+// _cacheSetContext is _cacheSet with ctx threaded through to a ContextStore.
+func _cacheSetContext[T any](ctx context.Context, c *Cache, func_name string, key string, value T, ttl time.Duration) {
+	serializedValue, err := _serialize(value)
+	if err != nil {
+		slog.Debug("Serialization error", "error", err)
+		return
+	}
+
+	if err := c._storeSetContext(ctx, func_name, key, serializedValue, time.Now().UTC(), ttl); err != nil {
+		slog.Debug("Cache Set Error:", "error", err)
+	}
 }
 
-// _generateKey hashes function arguments into a unique cache key
-func _generateKey(args ...any) string {
-	return fmt.Sprintf("%v", args)
+// _cacheGetRawContext fetches and deserializes a value without judging
+// expiry, returning the timestamp it was stored under so the caller (e.g.
+// MemoizeCtx*, which needs to honor a request-scoped TTL override and
+// stale-while-revalidate window) can apply its own freshness policy.
+func _cacheGetRawContext[T any](ctx context.Context, c *Cache, func_name string, key string) (T, time.Time, bool) {
+	var zero T
+
+	data, ts, found := c._storeGetContext(ctx, func_name, key)
+	if !found {
+		return zero, time.Time{}, false
+	}
+
+	item, err := _deserialize[T](data)
+	if err != nil {
+		slog.Debug("Deserialization error:", "error", err)
+		return zero, time.Time{}, false
+	}
+
+	return item, ts, true
 }
 
-func Memoize0[R any](ttl time.Duration, fn func() R) func() R {
+func _funcName(fn any) string {
+	return runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+}
+
+// Memoize0 wraps fn so its result is cached, for ttl, in the package-level
+// default Cache unless opts supplies WithCache. opts can also override
+// cache key derivation via WithKeyFunc. Concurrent callers computing the
+// same key share one execution of fn unless the Cache disables
+// singleflight.
+func Memoize0[R any](ttl time.Duration, fn func() R, opts ...MemoizeOption) func() R {
+	cfg := _newMemoizeConfig(opts)
+	c := _orDefault(cfg.cache)
 	func_name := _funcName(fn)
 	return func() R {
-		key := _generateKey()
-		item, found := _cacheGet[R](func_name, key, ttl)
+		key, err := cfg.keyFunc()
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn()
+		}
+		item, found := _cacheGet[R](c, func_name, key, ttl)
 
 		if found {
 			slog.Debug("Cache hit", "key", key)
 			return item
 		}
 
-		result := fn()
-		_cacheSet(func_name, key, result)
+		result := _callMemoized(c, func_name, key, func() R {
+			r := fn()
+			_cacheSet(c, func_name, key, r, ttl)
+			return r
+		})
 		slog.Debug("Cache miss", "key", key)
 		return result
 	}
 }
 
-func Memoize1[A, R any](ttl time.Duration, fn func(A) R) func(A) R {
+func Memoize1[A, R any](ttl time.Duration, fn func(A) R, opts ...MemoizeOption) func(A) R {
+	cfg := _newMemoizeConfig(opts)
+	c := _orDefault(cfg.cache)
 	func_name := _funcName(fn)
 	return func(arg A) R {
-		key := _generateKey(arg)
-		item, found := _cacheGet[R](func_name, key, ttl)
+		key, err := cfg.keyFunc(arg)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(arg)
+		}
+		item, found := _cacheGet[R](c, func_name, key, ttl)
 
 		if found {
 			slog.Debug("Cache hit:", "key", key)
 			return item
 		}
 
+		result := _callMemoized(c, func_name, key, func() R {
+			r := fn(arg)
+			_cacheSet(c, func_name, key, r, ttl)
+			return r
+		})
 		slog.Debug("Cache miss:", "key", key)
-		result := fn(arg)
-		_cacheSet(func_name, key, result)
 		return result
 	}
 }
 
-func Memoize2[A, B, R any](ttl time.Duration, fn func(A, B) R) func(A, B) R {
+func Memoize2[A, B, R any](ttl time.Duration, fn func(A, B) R, opts ...MemoizeOption) func(A, B) R {
+	cfg := _newMemoizeConfig(opts)
+	c := _orDefault(cfg.cache)
 	func_name := _funcName(fn)
 	return func(arg1 A, arg2 B) R {
-		key := _generateKey(arg1, arg2)
-		item, found := _cacheGet[R](func_name, key, ttl)
+		key, err := cfg.keyFunc(arg1, arg2)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(arg1, arg2)
+		}
+		item, found := _cacheGet[R](c, func_name, key, ttl)
 
 		if found {
 			slog.Debug("Cache hit:", "key", key)
 			return item
 		}
 
+		result := _callMemoized(c, func_name, key, func() R {
+			r := fn(arg1, arg2)
+			_cacheSet(c, func_name, key, r, ttl)
+			return r
+		})
 		slog.Debug("Cache miss:", "key", key)
-		result := fn(arg1, arg2)
-		_cacheSet(func_name, key, result)
 		return result
 	}
 }
 
-func Memoize3[A, B, C, R any](ttl time.Duration, fn func(A, B, C) R) func(A, B, C) R {
+func Memoize3[A, B, C, R any](ttl time.Duration, fn func(A, B, C) R, opts ...MemoizeOption) func(A, B, C) R {
+	cfg := _newMemoizeConfig(opts)
+	c := _orDefault(cfg.cache)
 	func_name := _funcName(fn)
 	return func(arg1 A, arg2 B, arg3 C) R {
-		key := _generateKey(arg1, arg2, arg3)
-		item, found := _cacheGet[R](func_name, key, ttl)
+		key, err := cfg.keyFunc(arg1, arg2, arg3)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(arg1, arg2, arg3)
+		}
+		item, found := _cacheGet[R](c, func_name, key, ttl)
 
 		if found {
 			slog.Debug("Cache hit:", "key", key)
 			return item
 		}
 
+		result := _callMemoized(c, func_name, key, func() R {
+			r := fn(arg1, arg2, arg3)
+			_cacheSet(c, func_name, key, r, ttl)
+			return r
+		})
 		slog.Debug("Cache miss:", "key", key)
-		result := fn(arg1, arg2, arg3)
-		_cacheSet(func_name, key, result)
 		return result
 	}
 }
 
-func Memoize4[A, B, C, D, R any](ttl time.Duration, fn func(A, B, C, D) R) func(A, B, C, D) R {
+func Memoize4[A, B, C, D, R any](ttl time.Duration, fn func(A, B, C, D) R, opts ...MemoizeOption) func(A, B, C, D) R {
+	cfg := _newMemoizeConfig(opts)
+	c := _orDefault(cfg.cache)
 	func_name := _funcName(fn)
 	return func(arg1 A, arg2 B, arg3 C, arg4 D) R {
-		key := _generateKey(arg1, arg2, arg3, arg4)
-		item, found := _cacheGet[R](func_name, key, ttl)
+		key, err := cfg.keyFunc(arg1, arg2, arg3, arg4)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(arg1, arg2, arg3, arg4)
+		}
+		item, found := _cacheGet[R](c, func_name, key, ttl)
 
 		if found {
 			slog.Debug("Cache hit:", "key", key)
 			return item
 		}
 
+		result := _callMemoized(c, func_name, key, func() R {
+			r := fn(arg1, arg2, arg3, arg4)
+			_cacheSet(c, func_name, key, r, ttl)
+			return r
+		})
 		slog.Debug("Cache miss:", "key", key)
-		result := fn(arg1, arg2, arg3, arg4)
-		_cacheSet(func_name, key, result)
 		return result
 	}
 }
 
-func Memoize5[A, B, C, D, E, R any](ttl time.Duration, fn func(A, B, C, D, E) R) func(A, B, C, D, E) R {
+func Memoize5[A, B, C, D, E, R any](ttl time.Duration, fn func(A, B, C, D, E) R, opts ...MemoizeOption) func(A, B, C, D, E) R {
+	cfg := _newMemoizeConfig(opts)
+	c := _orDefault(cfg.cache)
 	func_name := _funcName(fn)
 	return func(arg1 A, arg2 B, arg3 C, arg4 D, arg5 E) R {
-		key := _generateKey(arg1, arg2, arg3, arg4, arg5)
-		item, found := _cacheGet[R](func_name, key, ttl)
+		key, err := cfg.keyFunc(arg1, arg2, arg3, arg4, arg5)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(arg1, arg2, arg3, arg4, arg5)
+		}
+		item, found := _cacheGet[R](c, func_name, key, ttl)
 
 		if found {
 			slog.Debug("Cache hit:", "key", key)
 			return item
 		}
 
+		result := _callMemoized(c, func_name, key, func() R {
+			r := fn(arg1, arg2, arg3, arg4, arg5)
+			_cacheSet(c, func_name, key, r, ttl)
+			return r
+		})
 		slog.Debug("Cache miss:", "key", key)
-		result := fn(arg1, arg2, arg3, arg4, arg5)
-		_cacheSet(func_name, key, result)
 		return result
 	}
 }
 
-func Memoize6[A, B, C, D, E, F, R any](ttl time.Duration, fn func(A, B, C, D, E, F) R) func(A, B, C, D, E, F) R {
+func Memoize6[A, B, C, D, E, F, R any](ttl time.Duration, fn func(A, B, C, D, E, F) R, opts ...MemoizeOption) func(A, B, C, D, E, F) R {
+	cfg := _newMemoizeConfig(opts)
+	c := _orDefault(cfg.cache)
 	func_name := _funcName(fn)
 	return func(arg1 A, arg2 B, arg3 C, arg4 D, arg5 E, arg6 F) R {
-		key := _generateKey(arg1, arg2, arg3, arg4, arg5, arg6)
-		item, found := _cacheGet[R](func_name, key, ttl)
+		key, err := cfg.keyFunc(arg1, arg2, arg3, arg4, arg5, arg6)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(arg1, arg2, arg3, arg4, arg5, arg6)
+		}
+		item, found := _cacheGet[R](c, func_name, key, ttl)
 
 		if found {
 			slog.Debug("Cache hit:", "key", key)
 			return item
 		}
 
+		result := _callMemoized(c, func_name, key, func() R {
+			r := fn(arg1, arg2, arg3, arg4, arg5, arg6)
+			_cacheSet(c, func_name, key, r, ttl)
+			return r
+		})
 		slog.Debug("Cache miss:", "key", key)
-		result := fn(arg1, arg2, arg3, arg4, arg5, arg6)
-		_cacheSet(func_name, key, result)
 		return result
 	}
 }
 
-func Memoize7[A, B, C, D, E, F, G, R any](ttl time.Duration, fn func(A, B, C, D, E, F, G) R) func(A, B, C, D, E, F, G) R {
+func Memoize7[A, B, C, D, E, F, G, R any](ttl time.Duration, fn func(A, B, C, D, E, F, G) R, opts ...MemoizeOption) func(A, B, C, D, E, F, G) R {
+	cfg := _newMemoizeConfig(opts)
+	c := _orDefault(cfg.cache)
 	func_name := _funcName(fn)
 	return func(arg1 A, arg2 B, arg3 C, arg4 D, arg5 E, arg6 F, arg7 G) R {
-		key := _generateKey(arg1, arg2, arg3, arg4, arg5, arg6, arg7)
-		item, found := _cacheGet[R](func_name, key, ttl)
+		key, err := cfg.keyFunc(arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+		}
+		item, found := _cacheGet[R](c, func_name, key, ttl)
 
 		if found {
 			slog.Debug("Cache hit:", "key", key)
 			return item
 		}
 
+		result := _callMemoized(c, func_name, key, func() R {
+			r := fn(arg1, arg2, arg3, arg4, arg5, arg6, arg7)
+			_cacheSet(c, func_name, key, r, ttl)
+			return r
+		})
 		slog.Debug("Cache miss:", "key", key)
-		result := fn(arg1, arg2, arg3, arg4, arg5, arg6, arg7)
-		_cacheSet(func_name, key, result)
 		return result
 	}
 }
 
-func Memoize8[A, B, C, D, E, F, G, H, R any](ttl time.Duration, fn func(A, B, C, D, E, F, G, H) R) func(A, B, C, D, E, F, G, H) R {
+func Memoize8[A, B, C, D, E, F, G, H, R any](ttl time.Duration, fn func(A, B, C, D, E, F, G, H) R, opts ...MemoizeOption) func(A, B, C, D, E, F, G, H) R {
+	cfg := _newMemoizeConfig(opts)
+	c := _orDefault(cfg.cache)
 	func_name := _funcName(fn)
 	return func(arg1 A, arg2 B, arg3 C, arg4 D, arg5 E, arg6 F, arg7 G, arg8 H) R {
-		key := _generateKey(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
-		item, found := _cacheGet[R](func_name, key, ttl)
+		key, err := cfg.keyFunc(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
+		}
+		item, found := _cacheGet[R](c, func_name, key, ttl)
 
 		if found {
 			slog.Debug("Cache hit:", "key", key)
 			return item
 		}
 
+		result := _callMemoized(c, func_name, key, func() R {
+			r := fn(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
+			_cacheSet(c, func_name, key, r, ttl)
+			return r
+		})
 		slog.Debug("Cache miss:", "key", key)
-		result := fn(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
-		_cacheSet(func_name, key, result)
 		return result
 	}
 }
 
-func Memoize9[A, B, C, D, E, F, G, H, I, R any](ttl time.Duration, fn func(A, B, C, D, E, F, G, H, I) R) func(A, B, C, D, E, F, G, H, I) R {
+func Memoize9[A, B, C, D, E, F, G, H, I, R any](ttl time.Duration, fn func(A, B, C, D, E, F, G, H, I) R, opts ...MemoizeOption) func(A, B, C, D, E, F, G, H, I) R {
+	cfg := _newMemoizeConfig(opts)
+	c := _orDefault(cfg.cache)
 	func_name := _funcName(fn)
 	return func(arg1 A, arg2 B, arg3 C, arg4 D, arg5 E, arg6 F, arg7 G, arg8 H, arg9 I) R {
-		key := _generateKey(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9)
-		item, found := _cacheGet[R](func_name, key, ttl)
+		key, err := cfg.keyFunc(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9)
+		}
+		item, found := _cacheGet[R](c, func_name, key, ttl)
 
 		if found {
 			slog.Debug("Cache hit:", "key", key)
 			return item
 		}
 
+		result := _callMemoized(c, func_name, key, func() R {
+			r := fn(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9)
+			_cacheSet(c, func_name, key, r, ttl)
+			return r
+		})
 		slog.Debug("Cache miss:", "key", key)
-		result := fn(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9)
-		_cacheSet(func_name, key, result)
 		return result
 	}
 }
 
-func MemoizeN[R any](ttl time.Duration, fn func(args ...any) R) func(...any) R {
+func MemoizeN[R any](ttl time.Duration, fn func(args ...any) R, opts ...MemoizeOption) func(...any) R {
+	cfg := _newMemoizeConfig(opts)
+	c := _orDefault(cfg.cache)
 	func_name := _funcName(fn)
 	return func(args ...any) R {
-		key := _generateKey(args...)
-		item, found := _cacheGet[R](func_name, key, ttl)
+		key, err := cfg.keyFunc(args...)
+		if err != nil {
+			slog.Debug("Key generation error", "error", err)
+			return fn(args...)
+		}
+		item, found := _cacheGet[R](c, func_name, key, ttl)
 
 		if found {
 			slog.Debug("Cache hit:", "key", key)
 			return item
 		}
 
+		result := _callMemoized(c, func_name, key, func() R {
+			r := fn(args...)
+			_cacheSet(c, func_name, key, r, ttl)
+			return r
+		})
 		slog.Debug("Cache miss:", "key", key)
-		result := fn(args...)
-		_cacheSet(func_name, key, result)
 		return result
 	}
 }
-
-func DeleteFuncCache(fn any) {
-	func_name := _funcName(fn)
-	c := _getPersistentCache()
-	_, err := c.db.Exec("DELETE FROM cache WHERE function = ?", func_name)
-	if err != nil {
-		slog.Debug("Cache Delete Error:", "func_name", func_name)
-	}
-}
@@ -0,0 +1,34 @@
+package persistent_cache
+
+// _singleflightKey combines a function name and cache key into the key
+// singleflight.Group coalesces concurrent callers on.
+func _singleflightKey(funcName, key string) string {
+	return funcName + "\x00" + key
+}
+
+// _callMemoized invokes fn, coalescing concurrent callers for the same
+// funcName+key into a single execution when c's singleflight tier is
+// enabled (see CacheOptions.DisableSingleflight).
+func _callMemoized[R any](c *Cache, funcName, key string, fn func() R) R {
+	if c.sf == nil {
+		return fn()
+	}
+	v, _, _ := c.sf.Do(_singleflightKey(funcName, key), func() (any, error) {
+		return fn(), nil
+	})
+	result, _ := v.(R)
+	return result
+}
+
+// _callMemoizedErr is _callMemoized for fn that can also return an error,
+// as used by MemoizeErr*.
+func _callMemoizedErr[R any](c *Cache, funcName, key string, fn func() (R, error)) (R, error) {
+	if c.sf == nil {
+		return fn()
+	}
+	v, err, _ := c.sf.Do(_singleflightKey(funcName, key), func() (any, error) {
+		return fn()
+	})
+	result, _ := v.(R)
+	return result, err
+}
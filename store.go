@@ -0,0 +1,82 @@
+package persistent_cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by a Store's Get when no entry exists for the
+// given function/key pair.
+var ErrNotFound = errors.New("persistent_cache: entry not found")
+
+// Store is the backend a Cache persists memoized results through. Adapters
+// register themselves with Register (see the sqlite, memory and redis
+// adapters shipped alongside this package) so callers can pick one by name
+// via NewCache, the same way database/sql picks a driver.
+type Store interface {
+	// Get returns the serialized value and insertion timestamp previously
+	// passed to Set for funcName/key, or ErrNotFound if no entry exists.
+	Get(funcName, key string) ([]byte, time.Time, error)
+	// Set persists value under funcName/key, recording ts as its insertion
+	// time and ttl so the caller (or a janitor, for stores that implement
+	// Sweeper) can evaluate expiry.
+	Set(funcName, key string, value []byte, ts time.Time, ttl time.Duration) error
+	// Delete removes a single funcName/key entry, if present.
+	Delete(funcName, key string) error
+	// DeleteFunc removes every entry cached for funcName.
+	DeleteFunc(funcName string) error
+	// Close releases any resources held by the Store.
+	Close() error
+}
+
+// EvictedEntry identifies a cache entry a Sweeper removed because it had
+// expired.
+type EvictedEntry struct {
+	FuncName string
+	Key      string
+}
+
+// Sweeper is implemented by stores that can purge every expired entry in a
+// single pass. Cache.StartJanitor uses it to run a background janitor;
+// stores that expire entries natively (e.g. the redis adapter) need not
+// implement it.
+type Sweeper interface {
+	Sweep(now time.Time) ([]EvictedEntry, error)
+}
+
+// ContextStore is implemented by stores that can honor a context's
+// deadline and cancellation on individual operations (see MemoizeCtx*).
+// Cache prefers it over Store's plain methods when available; stores
+// that never block (e.g. the in-memory adapter) need not implement it.
+type ContextStore interface {
+	GetContext(ctx context.Context, funcName, key string) ([]byte, time.Time, error)
+	SetContext(ctx context.Context, funcName, key string, value []byte, ts time.Time, ttl time.Duration) error
+	DeleteContext(ctx context.Context, funcName, key string) error
+}
+
+// AdapterFunc constructs a Store from an adapter-specific config string,
+// e.g. a file path or DSN.
+type AdapterFunc func(config string) (Store, error)
+
+var (
+	adaptersMu sync.RWMutex
+	adapters   = make(map[string]AdapterFunc)
+)
+
+// Register makes a Store adapter available under name for use with
+// NewCache. It is intended to be called from an adapter's init function,
+// mirroring database/sql.Register, and panics on a nil adapter or a
+// duplicate name.
+func Register(name string, adapter AdapterFunc) {
+	adaptersMu.Lock()
+	defer adaptersMu.Unlock()
+	if adapter == nil {
+		panic("persistent_cache: Register adapter is nil")
+	}
+	if _, dup := adapters[name]; dup {
+		panic("persistent_cache: Register called twice for adapter " + name)
+	}
+	adapters[name] = adapter
+}
@@ -0,0 +1,93 @@
+package persistent_cache
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	Register("memory", newMemoryStore)
+}
+
+type memoryEntry struct {
+	value []byte
+	ts    time.Time
+	ttl   time.Duration
+}
+
+// memoryStore is an in-memory Store backed by a map. Entries do not
+// survive a process restart; config is ignored.
+type memoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]memoryEntry
+}
+
+func newMemoryStore(config string) (Store, error) {
+	return &memoryStore{entries: make(map[string]memoryEntry)}, nil
+}
+
+func memoryKey(funcName, key string) string {
+	return funcName + "\x00" + key
+}
+
+func splitMemoryKey(k string) (funcName, key string) {
+	funcName, key, _ = strings.Cut(k, "\x00")
+	return funcName, key
+}
+
+func (s *memoryStore) Get(funcName, key string) ([]byte, time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.entries[memoryKey(funcName, key)]
+	if !ok {
+		return nil, time.Time{}, ErrNotFound
+	}
+	return entry.value, entry.ts, nil
+}
+
+func (s *memoryStore) Set(funcName, key string, value []byte, ts time.Time, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[memoryKey(funcName, key)] = memoryEntry{value: value, ts: ts, ttl: ttl}
+	return nil
+}
+
+func (s *memoryStore) Delete(funcName, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, memoryKey(funcName, key))
+	return nil
+}
+
+func (s *memoryStore) DeleteFunc(funcName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prefix := funcName + "\x00"
+	for k := range s.entries {
+		if strings.HasPrefix(k, prefix) {
+			delete(s.entries, k)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+// Sweep deletes every entry whose ts+ttl has passed and reports what it
+// removed, so Cache.StartJanitor can fire OnEvicted.
+func (s *memoryStore) Sweep(now time.Time) ([]EvictedEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var evicted []EvictedEntry
+	for k, entry := range s.entries {
+		if !now.Before(entry.ts.Add(entry.ttl)) {
+			funcName, key := splitMemoryKey(k)
+			evicted = append(evicted, EvictedEntry{FuncName: funcName, Key: key})
+			delete(s.entries, k)
+		}
+	}
+	return evicted, nil
+}
@@ -0,0 +1,103 @@
+package persistent_cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func init() {
+	Register("redis", newRedisStore)
+}
+
+// redisStore persists cache entries to Redis. config is a DSN as accepted
+// by redis.ParseURL, e.g. "redis://localhost:6379/0".
+type redisStore struct {
+	client *redis.Client
+}
+
+func newRedisStore(config string) (Store, error) {
+	opts, err := redis.ParseURL(config)
+	if err != nil {
+		return nil, err
+	}
+	return &redisStore{client: redis.NewClient(opts)}, nil
+}
+
+func redisKey(funcName, key string) string {
+	return "persistent_cache:" + funcName + ":" + key
+}
+
+func (s *redisStore) Get(funcName, key string) ([]byte, time.Time, error) {
+	return s.GetContext(context.Background(), funcName, key)
+}
+
+// Set writes the entry and, when ttl is positive, lets Redis expire it
+// natively rather than relying on a janitor sweep.
+func (s *redisStore) Set(funcName, key string, value []byte, ts time.Time, ttl time.Duration) error {
+	return s.SetContext(context.Background(), funcName, key, value, ts, ttl)
+}
+
+func (s *redisStore) Delete(funcName, key string) error {
+	return s.DeleteContext(context.Background(), funcName, key)
+}
+
+// GetContext is Get with ctx threaded through the HMGet call.
+func (s *redisStore) GetContext(ctx context.Context, funcName, key string) ([]byte, time.Time, error) {
+	vals, err := s.client.HMGet(ctx, redisKey(funcName, key), "result", "timestamp").Result()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if vals[0] == nil || vals[1] == nil {
+		return nil, time.Time{}, ErrNotFound
+	}
+	data := []byte(vals[0].(string))
+	ts, err := strconv.ParseInt(vals[1].(string), 10, 64)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return data, time.Unix(ts, 0).UTC(), nil
+}
+
+// SetContext is Set with ctx threaded through the pipelined HSet/Expire.
+func (s *redisStore) SetContext(ctx context.Context, funcName, key string, value []byte, ts time.Time, ttl time.Duration) error {
+	k := redisKey(funcName, key)
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, k, map[string]any{
+		"result":    value,
+		"timestamp": ts.Unix(),
+	})
+	if ttl > 0 {
+		pipe.Expire(ctx, k, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// DeleteContext is Delete with ctx threaded through the Del call.
+func (s *redisStore) DeleteContext(ctx context.Context, funcName, key string) error {
+	return s.client.Del(ctx, redisKey(funcName, key)).Err()
+}
+
+func (s *redisStore) DeleteFunc(funcName string) error {
+	ctx := context.Background()
+	pattern := "persistent_cache:" + funcName + ":*"
+	iter := s.client.Scan(ctx, 0, pattern, 0).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return s.client.Del(ctx, keys...).Err()
+}
+
+func (s *redisStore) Close() error {
+	return s.client.Close()
+}
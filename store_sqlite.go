@@ -0,0 +1,128 @@
+package persistent_cache
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	_ "modernc.org/sqlite" // SQLite driver
+)
+
+func init() {
+	Register("sqlite", newSQLiteStore)
+}
+
+// sqliteStore persists cache entries to a SQLite database file.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// newSQLiteStore opens (creating if necessary) the SQLite database at
+// config and ensures the cache table exists. config is a file path, or
+// ":memory:" for a private in-process database.
+func newSQLiteStore(config string) (Store, error) {
+	db, err := sql.Open("sqlite", config)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS cache (
+		id INTEGER PRIMARY KEY,
+		function TEXT,
+		args BLOB,
+		result BLOB,
+		timestamp_ms INTEGER NOT NULL,
+		ttl_ms INTEGER NOT NULL DEFAULT 0,
+		UNIQUE(function, args)
+	);`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) Get(funcName, key string) ([]byte, time.Time, error) {
+	return s.GetContext(context.Background(), funcName, key)
+}
+
+func (s *sqliteStore) Set(funcName, key string, value []byte, ts time.Time, ttl time.Duration) error {
+	return s.SetContext(context.Background(), funcName, key, value, ts, ttl)
+}
+
+func (s *sqliteStore) Delete(funcName, key string) error {
+	return s.DeleteContext(context.Background(), funcName, key)
+}
+
+// GetContext is Get with ctx routed through QueryRowContext, so a caller
+// using MemoizeCtx* can cancel a slow lookup.
+func (s *sqliteStore) GetContext(ctx context.Context, funcName, key string) ([]byte, time.Time, error) {
+	var data []byte
+	var timestampMs int64
+	err := s.db.
+		QueryRowContext(ctx, "SELECT result, timestamp_ms FROM cache WHERE function = ? AND args = ?", funcName, key).
+		Scan(&data, &timestampMs)
+	if err == sql.ErrNoRows {
+		return nil, time.Time{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return data, time.UnixMilli(timestampMs).UTC(), nil
+}
+
+// SetContext is Set with ctx routed through ExecContext. timestamp_ms and
+// ttl_ms are stored at millisecond resolution so the janitor's Sweep
+// predicate agrees with the lazy expiry check in _cacheGet, which compares
+// against the full ttl time.Duration a caller passed in: seconds-only
+// storage silently rounded sub-second ttls down to 0 and expired them a
+// full tick before the lazy path would have.
+func (s *sqliteStore) SetContext(ctx context.Context, funcName, key string, value []byte, ts time.Time, ttl time.Duration) error {
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO cache (function, args, result, timestamp_ms, ttl_ms) VALUES (?, ?, ?, ?, ?) "+
+			"ON CONFLICT(function, args) DO UPDATE SET result = excluded.result, timestamp_ms = excluded.timestamp_ms, ttl_ms = excluded.ttl_ms",
+		funcName, key, value, ts.UnixMilli(), ttl.Milliseconds())
+	return err
+}
+
+// DeleteContext is Delete with ctx routed through ExecContext.
+func (s *sqliteStore) DeleteContext(ctx context.Context, funcName, key string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM cache WHERE function = ? AND args = ?", funcName, key)
+	return err
+}
+
+func (s *sqliteStore) DeleteFunc(funcName string) error {
+	_, err := s.db.Exec("DELETE FROM cache WHERE function = ?", funcName)
+	return err
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+// Sweep deletes every row whose timestamp_ms+ttl_ms has passed and reports
+// what it removed, so Cache.StartJanitor can fire OnEvicted. It deletes
+// with RETURNING rather than a SELECT followed by a separate DELETE, so a
+// row a concurrent SetContext refreshes between the two statements can't
+// be reported as evicted while surviving the delete.
+func (s *sqliteStore) Sweep(now time.Time) ([]EvictedEntry, error) {
+	rows, err := s.db.Query("DELETE FROM cache WHERE timestamp_ms + ttl_ms <= ? RETURNING function, args", now.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var evicted []EvictedEntry
+	for rows.Next() {
+		var e EvictedEntry
+		if err := rows.Scan(&e.FuncName, &e.Key); err != nil {
+			return nil, err
+		}
+		evicted = append(evicted, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return evicted, nil
+}
@@ -0,0 +1,51 @@
+package persistent_cache
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSQLiteStoreSweepSubSecondTTL guards against the janitor sweeping a
+// sub-second ttl earlier than Get/_cacheGet would consider it expired: both
+// paths must agree on whether a given (timestamp, ttl) pair has expired at
+// a given instant.
+func TestSQLiteStoreSweepSubSecondTTL(t *testing.T) {
+	store, err := newSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("newSQLiteStore: %v", err)
+	}
+	defer store.Close()
+
+	ts := time.Now().UTC()
+	ttl := 500 * time.Millisecond
+	if err := store.Set("fn", "k", []byte("v"), ts, ttl); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	sweeper := store.(Sweeper)
+
+	// 300ms in: _cacheGet would consider this live (ts+ttl still ahead),
+	// so the janitor must not have evicted it yet either.
+	evicted, err := sweeper.Sweep(ts.Add(300 * time.Millisecond))
+	if err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	if len(evicted) != 0 {
+		t.Fatalf("Sweep evicted %d entries at 300ms, want 0 (ttl is 500ms)", len(evicted))
+	}
+	if _, _, err := store.Get("fn", "k"); err != nil {
+		t.Fatalf("Get after early sweep: %v", err)
+	}
+
+	// 600ms in: now past the ttl, so the janitor should catch it.
+	evicted, err = sweeper.Sweep(ts.Add(600 * time.Millisecond))
+	if err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	if len(evicted) != 1 {
+		t.Fatalf("Sweep evicted %d entries at 600ms, want 1", len(evicted))
+	}
+	if _, _, err := store.Get("fn", "k"); err != ErrNotFound {
+		t.Fatalf("Get after sweep: err = %v, want ErrNotFound", err)
+	}
+}